@@ -0,0 +1,56 @@
+/*
+Copyright 2014 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package etcd
+
+import (
+	"crypto/sha1"
+	"fmt"
+
+	"k8s.io/kubernetes/pkg/api"
+	etcdgeneric "k8s.io/kubernetes/pkg/registry/generic/etcd"
+)
+
+// aggregatePrefix is kept separate from the event list's own prefix
+// ("/events") so that aggregate bookkeeping records never show up in a
+// List/Watch over events: they share a namespace scheme but live under a
+// root the event REST's KeyRootFunc never scans.
+const aggregatePrefix = "/event-aggregates"
+
+// aggregationKey returns the string that identifies the series of events
+// that should be folded together: the involved object, the component/host
+// that produced the event, and the reason and type. The message is
+// deliberately left out: real messages almost always embed something
+// event-specific (an image tag, a pod name, a byte count, ...), so hashing
+// the raw message would give every occurrence of what is conceptually the
+// same event its own aggregation key and defeat aggregation entirely.
+func aggregationKey(e *api.Event) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "%s|%s|%s|%s|%s",
+		e.InvolvedObject.UID,
+		e.Source.Component,
+		e.Source.Host,
+		e.Reason,
+		e.Type)
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// aggregatedEventKeyFunc returns the deterministic etcd key under which the
+// aggregate record for event is kept: /event-aggregates/<namespace>/<aggregationKey(e)>,
+// where aggregationKey(e) is already a hex sha1 digest.
+func aggregatedEventKeyFunc(ctx api.Context, e *api.Event) (string, error) {
+	return etcdgeneric.NamespaceKeyFunc(ctx, aggregatePrefix, aggregationKey(e))
+}
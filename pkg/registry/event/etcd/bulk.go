@@ -0,0 +1,83 @@
+/*
+Copyright 2014 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package etcd
+
+import (
+	"fmt"
+
+	"k8s.io/kubernetes/pkg/api"
+	etcdgeneric "k8s.io/kubernetes/pkg/registry/generic/etcd"
+	"k8s.io/kubernetes/pkg/runtime"
+)
+
+// assert that *REST satisfies the generic bulk-create extension point.
+var _ etcdgeneric.BulkCreater = &REST{}
+
+// CreateCollection creates every event in list as a single logical
+// operation. If any event fails validation, nothing is written at all;
+// otherwise each event is written via its own Create call (so each one is
+// still folded into an aggregate record when one applies) and the result,
+// including any per-item error, is returned in the same order as the
+// input, so a failure partway through the batch doesn't silently swallow
+// the rest.
+//
+// Despite the name, this does not wrap the batch in a single etcd
+// transaction: etcd v2 (what tools.EtcdClient speaks here) has no
+// multi-key atomic write, so there is nothing to batch the round trips
+// into. What CreateCollection buys over the caller looping Create itself
+// is the all-or-nothing validation pass below, plus a single place that
+// fixes the per-item result ordering and error reporting contract.
+func (r *REST) CreateCollection(ctx api.Context, list runtime.Object) (runtime.Object, []error) {
+	events, ok := list.(*api.EventList)
+	if !ok {
+		return nil, []error{fmt.Errorf("not an event list: %#v", list)}
+	}
+
+	validationErrs := make([]error, len(events.Items))
+	anyInvalid := false
+	for i := range events.Items {
+		if err := validateEventForCreate(&events.Items[i]); err != nil {
+			validationErrs[i] = fmt.Errorf("event at index %d is invalid: %v", i, err)
+			anyInvalid = true
+		}
+	}
+	if anyInvalid {
+		return nil, validationErrs
+	}
+
+	out := &api.EventList{Items: make([]api.Event, 0, len(events.Items))}
+	errs := make([]error, len(events.Items))
+	for i := range events.Items {
+		created, err := r.Create(ctx, &events.Items[i])
+		errs[i] = err
+		if err != nil {
+			continue
+		}
+		out.Items = append(out.Items, *created.(*api.Event))
+	}
+	return out, errs
+}
+
+// validateEventForCreate is the minimal check CreateCollection needs to
+// reject a malformed batch before any write happens; the usual per-object
+// validation still runs inside Create for each item that does get written.
+func validateEventForCreate(e *api.Event) error {
+	if e.Name == "" && e.GenerateName == "" {
+		return fmt.Errorf("name or generateName is required")
+	}
+	return nil
+}
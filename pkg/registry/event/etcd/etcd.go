@@ -0,0 +1,290 @@
+/*
+Copyright 2014 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package etcd
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/fields"
+	"k8s.io/kubernetes/pkg/labels"
+	"k8s.io/kubernetes/pkg/registry/event"
+	"k8s.io/kubernetes/pkg/registry/generic"
+	etcdgeneric "k8s.io/kubernetes/pkg/registry/generic/etcd"
+	"k8s.io/kubernetes/pkg/runtime"
+	"k8s.io/kubernetes/pkg/storage"
+	"k8s.io/kubernetes/pkg/util"
+)
+
+// REST implements the RESTStorage interface for events against etcd. In
+// addition to the plain CRUD behavior provided by the embedded *etcdgeneric.Etcd,
+// Create folds events that look like repeats of something already seen
+// (same involved object, source, reason and type, within a configurable
+// window) into a single aggregated record instead of writing a new object
+// per occurrence, and both Create and Update are subject to a per-source
+// and per-namespace rate limit on top of that.
+type REST struct {
+	*etcdgeneric.Etcd
+
+	ttl uint64
+
+	aggregationWindow       time.Duration
+	maxEventsPerAggregation int
+	aggregationBurst        int
+
+	qps               float64
+	burst             int
+	perNamespaceQPS   float64
+	perNamespaceBurst int
+	nowFunc           func() time.Time
+
+	limitersMu        sync.Mutex
+	sourceLimiters    map[string]*tokenBucket
+	namespaceLimiters map[string]*tokenBucket
+}
+
+const (
+	// defaultAggregationWindow bounds how long a series of matching events
+	// may be folded into a single aggregate record before a fresh one is
+	// started.
+	defaultAggregationWindow = 10 * time.Minute
+
+	// defaultMaxEventsPerAggregation caps Count on a single aggregate record
+	// before a still-recurring event rolls over into a new one.
+	defaultMaxEventsPerAggregation = 1000
+
+	// defaultAggregationBurst allows this many occurrences of an event
+	// before the aggregation window is strictly enforced, to smooth over
+	// bursts such as a controller replaying its whole state at startup.
+	defaultAggregationBurst = 25
+)
+
+// Option overrides one of the aggregation tunables on the event REST storage.
+type Option func(*REST)
+
+// WithAggregationWindow overrides how long a series of matching events may
+// be folded into a single aggregate record.
+func WithAggregationWindow(d time.Duration) Option {
+	return func(r *REST) { r.aggregationWindow = d }
+}
+
+// WithMaxEventsPerAggregation overrides how many occurrences may be folded
+// into a single aggregate record before a new one is started.
+func WithMaxEventsPerAggregation(max int) Option {
+	return func(r *REST) { r.maxEventsPerAggregation = max }
+}
+
+// WithAggregationBurst overrides how many occurrences of an event are
+// allowed before the aggregation window is strictly enforced.
+func WithAggregationBurst(burst int) Option {
+	return func(r *REST) { r.aggregationBurst = burst }
+}
+
+// WithEventQPS overrides the steady-state rate, per (namespace, source
+// component, source host), at which Create/Update calls are admitted.
+func WithEventQPS(qps float64) Option {
+	return func(r *REST) { r.qps = qps }
+}
+
+// WithEventBurst overrides how many events a single source may write back
+// to back before the steady-state rate kicks in.
+func WithEventBurst(burst int) Option {
+	return func(r *REST) { r.burst = burst }
+}
+
+// WithPerNamespaceQPS overrides the steady-state combined rate of all
+// sources within a single namespace.
+func WithPerNamespaceQPS(qps float64) Option {
+	return func(r *REST) { r.perNamespaceQPS = qps }
+}
+
+// WithPerNamespaceBurst overrides the combined burst allowance for all
+// sources within a single namespace.
+func WithPerNamespaceBurst(burst int) Option {
+	return func(r *REST) { r.perNamespaceBurst = burst }
+}
+
+// withClock overrides the REST's notion of the current time; it exists so
+// tests can drive the rate limiter forward deterministically instead of
+// sleeping.
+func withClock(nowFunc func() time.Time) Option {
+	return func(r *REST) { r.nowFunc = nowFunc }
+}
+
+// NewStorageForBackend is a convenience wrapper around NewStorage that
+// looks up the storage.Interface implementation by name (see
+// storage.RegisterBackend) instead of requiring the caller to construct one
+// directly. It lets the event REST run against etcd, the in-memory backend
+// used in tests, or any other backend registered under name, without this
+// package importing any of them.
+func NewStorageForBackend(name string, config interface{}, ttl uint64, opts ...Option) (*REST, error) {
+	s, err := storage.NewStorage(name, config)
+	if err != nil {
+		return nil, err
+	}
+	return NewStorage(s, ttl, opts...), nil
+}
+
+// NewStorage returns a RESTStorage object that will work against events.
+func NewStorage(s storage.Interface, ttl uint64, opts ...Option) *REST {
+	prefix := "/events"
+	store := &etcdgeneric.Etcd{
+		NewFunc:     func() runtime.Object { return &api.Event{} },
+		NewListFunc: func() runtime.Object { return &api.EventList{} },
+		KeyRootFunc: func(ctx api.Context) string {
+			return etcdgeneric.NamespaceKeyRootFunc(ctx, prefix)
+		},
+		KeyFunc: func(ctx api.Context, name string) (string, error) {
+			return etcdgeneric.NamespaceKeyFunc(ctx, prefix, name)
+		},
+		ObjectNameFunc: func(obj runtime.Object) (string, error) {
+			return obj.(*api.Event).Name, nil
+		},
+		PredicateFunc: func(label labels.Selector, field fields.Selector) generic.Matcher {
+			return event.MatchEvent(label, field)
+		},
+		EndpointName: "events",
+
+		TTLFunc: func(obj runtime.Object, existing uint64, update bool) (uint64, error) {
+			return ttl, nil
+		},
+
+		Storage: s,
+	}
+
+	r := &REST{
+		Etcd: store,
+		ttl:  ttl,
+
+		aggregationWindow:       defaultAggregationWindow,
+		maxEventsPerAggregation: defaultMaxEventsPerAggregation,
+		aggregationBurst:        defaultAggregationBurst,
+
+		qps:               defaultEventQPS,
+		burst:             defaultEventBurst,
+		perNamespaceQPS:   defaultPerNamespaceQPS,
+		perNamespaceBurst: defaultEventBurst * 5,
+		sourceLimiters:    map[string]*tokenBucket{},
+		namespaceLimiters: map[string]*tokenBucket{},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Update is Update, preceded by the same per-source/per-namespace rate
+// limit check as Create.
+func (r *REST) Update(ctx api.Context, obj runtime.Object) (runtime.Object, bool, error) {
+	newEvent, ok := obj.(*api.Event)
+	if !ok {
+		return nil, false, fmt.Errorf("not an event: %#v", obj)
+	}
+	if allowed, retryAfter := r.allow(newEvent); !allowed {
+		return nil, false, tooManyEventsError(newEvent, retryAfter)
+	}
+	return r.Etcd.Update(ctx, obj)
+}
+
+// Create stores a new event, folding it into an existing aggregate record
+// when an equivalent event was already seen within the aggregation window
+// instead of writing a second near-duplicate object to etcd. Create (and
+// Update) are also subject to a token-bucket budget per
+// (namespace, source component, source host): once that budget is
+// exhausted, writes are rejected with a TooManyRequests error instead of
+// being persisted, even if they would otherwise have folded into an
+// existing aggregate.
+func (r *REST) Create(ctx api.Context, obj runtime.Object) (runtime.Object, error) {
+	newEvent, ok := obj.(*api.Event)
+	if !ok {
+		return nil, fmt.Errorf("not an event: %#v", obj)
+	}
+
+	if allowed, retryAfter := r.allow(newEvent); !allowed {
+		return nil, tooManyEventsError(newEvent, retryAfter)
+	}
+
+	aggKey, err := aggregatedEventKeyFunc(ctx, newEvent)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := &api.Event{}
+	getErr := r.Storage.Get(ctx, aggKey, existing, false)
+	if getErr == nil && withinAggregationWindow(existing, r.now(), r.aggregationWindow, r.maxEventsPerAggregation, r.aggregationBurst) {
+		updated := &api.Event{}
+		ttl := r.ttl
+		err := r.Storage.GuaranteedUpdate(ctx, aggKey, updated, true, func(in runtime.Object, _ storage.ResponseMeta) (runtime.Object, *uint64, error) {
+			cur, ok := in.(*api.Event)
+			if !ok || !withinAggregationWindow(cur, r.now(), r.aggregationWindow, r.maxEventsPerAggregation, r.aggregationBurst) {
+				merged := *newEvent
+				merged.Count = 1
+				merged.LastTimestamp = util.NewTime(r.now())
+				return &merged, &ttl, nil
+			}
+			merged := *cur
+			merged.Count++
+			merged.LastTimestamp = util.NewTime(r.now())
+			return &merged, &ttl, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return updated, nil
+	}
+
+	created, err := r.Etcd.Create(ctx, newEvent)
+	if err != nil {
+		return nil, err
+	}
+
+	// Seed the aggregation record so the next occurrence of this event can
+	// be folded in. LastTimestamp is stamped from the clock rather than
+	// trusting newEvent's (possibly zero) value, so an event posted without
+	// one still aggregates correctly the next time it's seen. This is
+	// best-effort: if it fails, the event we just created is still valid,
+	// it just won't be used as an aggregation seed.
+	seed := *newEvent
+	seed.Count = 1
+	seed.LastTimestamp = util.NewTime(r.now())
+	r.Storage.Set(ctx, aggKey, &seed, &api.Event{}, r.ttl)
+
+	return created, nil
+}
+
+// withinAggregationWindow reports whether event e is recent (as of now, the
+// REST's injectable clock rather than wall-clock time, so this is
+// deterministic in tests) and small enough that one more matching
+// occurrence should be folded into it rather than starting a fresh
+// aggregate record. The first burst occurrences of a series are always
+// folded in regardless of elapsed time, so a flood of matching events (e.g.
+// a controller replaying its whole state at startup) doesn't each start
+// their own aggregate before the window has had a chance to apply.
+func withinAggregationWindow(e *api.Event, now time.Time, window time.Duration, maxCount int, burst int) bool {
+	if e == nil {
+		return false
+	}
+	if maxCount > 0 && int(e.Count) >= maxCount {
+		return false
+	}
+	if burst > 0 && int(e.Count) < burst {
+		return true
+	}
+	return now.Sub(e.LastTimestamp.Time) <= window
+}
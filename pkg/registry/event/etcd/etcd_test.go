@@ -19,6 +19,7 @@ package etcd
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"k8s.io/kubernetes/pkg/api"
 	"k8s.io/kubernetes/pkg/api/errors"
@@ -26,6 +27,7 @@ import (
 	etcdgeneric "k8s.io/kubernetes/pkg/registry/generic/etcd"
 	"k8s.io/kubernetes/pkg/runtime"
 	etcdstorage "k8s.io/kubernetes/pkg/storage/etcd"
+	_ "k8s.io/kubernetes/pkg/storage/memory"
 	"k8s.io/kubernetes/pkg/tools"
 	"k8s.io/kubernetes/pkg/tools/etcdtest"
 	"k8s.io/kubernetes/pkg/util"
@@ -35,13 +37,24 @@ import (
 
 var testTTL uint64 = 60
 
+// NewTestEventStorage builds event REST storage against the "etcd" backend
+// registered by pkg/storage/etcd, fronted by tools.FakeEtcdClient, so this
+// test exercises the same storage.Factory path production code uses
+// instead of wiring etcdstorage.NewEtcdStorage directly.
 func NewTestEventStorage(t *testing.T) (*tools.FakeEtcdClient, *REST) {
 	f := tools.NewFakeEtcdClient(t)
 	f.HideExpires = true
 	f.TestIndex = true
 
-	s := etcdstorage.NewEtcdStorage(f, testapi.Codec(), etcdtest.PathPrefix())
-	return f, NewStorage(s, testTTL)
+	rest, err := NewStorageForBackend("etcd", etcdstorage.Config{
+		Client: f,
+		Codec:  testapi.Codec(),
+		Prefix: etcdtest.PathPrefix(),
+	}, testTTL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return f, rest
 }
 
 func TestEventCreate(t *testing.T) {
@@ -81,11 +94,59 @@ func TestEventCreate(t *testing.T) {
 		t.Errorf("Unexpected error: %v", err)
 	}
 
+	// eventA2 and eventC look like further occurrences of, respectively, the
+	// same and a different series of events than eventA, and are used to
+	// exercise the aggregation path below. They get their own etcd keys,
+	// derived from the event content rather than the object name.
+	eventA2 := &api.Event{
+		ObjectMeta:     api.ObjectMeta{Name: "foo2", Namespace: api.NamespaceDefault},
+		Reason:         "forTesting",
+		InvolvedObject: api.ObjectReference{Name: "bar", Namespace: api.NamespaceDefault},
+	}
+	eventC := &api.Event{
+		ObjectMeta:     api.ObjectMeta{Name: "foo3", Namespace: api.NamespaceDefault},
+		Reason:         "differentReason",
+		InvolvedObject: api.ObjectReference{Name: "bar", Namespace: api.NamespaceDefault},
+	}
+
+	aggPathA, err := aggregatedEventKeyFunc(ctx, eventA)
+	aggPathA = etcdtest.AddPrefix(aggPathA)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	aggPathC, err := aggregatedEventKeyFunc(ctx, eventC)
+	aggPathC = etcdtest.AddPrefix(aggPathC)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	aggregateOfA := &api.Event{
+		ObjectMeta:     api.ObjectMeta{Name: "foo", Namespace: api.NamespaceDefault},
+		Reason:         "forTesting",
+		InvolvedObject: api.ObjectReference{Name: "bar", Namespace: api.NamespaceDefault},
+		Count:          1,
+		LastTimestamp:  util.Now(),
+	}
+	nodeWithAggregateOfA := tools.EtcdResponseWithError{
+		R: &etcd.Response{
+			Node: &etcd.Node{
+				Value:         runtime.EncodeOrDie(testapi.Codec(), aggregateOfA),
+				ModifiedIndex: 1,
+				CreatedIndex:  1,
+				TTL:           int64(testTTL),
+			},
+		},
+		E: nil,
+	}
+
 	table := map[string]struct {
-		existing tools.EtcdResponseWithError
-		expect   tools.EtcdResponseWithError
-		toCreate runtime.Object
-		errOK    func(error) bool
+		existing      tools.EtcdResponseWithError
+		seedAggregate tools.EtcdResponseWithError
+		aggPath       string
+		expect        tools.EtcdResponseWithError
+		checkPath     string
+		toCreate      runtime.Object
+		errOK         func(error) bool
 	}{
 		"normal": {
 			existing: emptyNode,
@@ -99,32 +160,136 @@ func TestEventCreate(t *testing.T) {
 			toCreate: eventB,
 			errOK:    errors.IsAlreadyExists,
 		},
+		"second identical event increments count": {
+			existing:      emptyNode,
+			seedAggregate: nodeWithAggregateOfA,
+			aggPath:       aggPathA,
+			checkPath:     aggPathA,
+			toCreate:      eventA2,
+			errOK:         func(err error) bool { return err == nil },
+		},
+		"distinct reasons do not collide": {
+			existing:      emptyNode,
+			seedAggregate: nodeWithAggregateOfA,
+			aggPath:       aggPathA,
+			checkPath:     aggPathC,
+			toCreate:      eventC,
+			errOK:         func(err error) bool { return err == nil },
+		},
 	}
 
 	for name, item := range table {
 		fakeClient, storage := NewTestEventStorage(t)
 		fakeClient.Data[path] = item.existing
+		if item.aggPath != "" {
+			fakeClient.Data[item.aggPath] = item.seedAggregate
+		}
 		_, err := storage.Create(ctx, item.toCreate)
 		if !item.errOK(err) {
 			t.Errorf("%v: unexpected error: %v", name, err)
 		}
 
-		// nullify fields set by infrastructure
-		received := fakeClient.Data[path]
-		var event api.Event
-		if err := testapi.Codec().DecodeInto([]byte(received.R.Node.Value), &event); err != nil {
-			t.Errorf("unexpected error: %v", err)
+		checkPath := item.checkPath
+		if checkPath == "" {
+			checkPath = path
 		}
-		event.ObjectMeta.CreationTimestamp = util.Time{}
-		event.ObjectMeta.UID = ""
-		received.R.Node.Value = runtime.EncodeOrDie(testapi.Codec(), &event)
 
-		if e, a := item.expect, received; !reflect.DeepEqual(e, a) {
-			t.Errorf("%v:\n%s", name, util.ObjectDiff(e, a))
+		switch name {
+		case "second identical event increments count":
+			received := fakeClient.Data[checkPath]
+			var event api.Event
+			if err := testapi.Codec().DecodeInto([]byte(received.R.Node.Value), &event); err != nil {
+				t.Errorf("unexpected error: %v", err)
+				continue
+			}
+			if event.Count != 2 {
+				t.Errorf("%v: expected Count 2, got %d", name, event.Count)
+			}
+		case "distinct reasons do not collide":
+			received := fakeClient.Data[checkPath]
+			var event api.Event
+			if err := testapi.Codec().DecodeInto([]byte(received.R.Node.Value), &event); err != nil {
+				t.Errorf("unexpected error: %v", err)
+				continue
+			}
+			if event.Count != 1 {
+				t.Errorf("%v: expected Count 1, got %d", name, event.Count)
+			}
+			// the original aggregate for eventA must be untouched.
+			untouched := fakeClient.Data[aggPathA]
+			var original api.Event
+			if err := testapi.Codec().DecodeInto([]byte(untouched.R.Node.Value), &original); err != nil {
+				t.Errorf("unexpected error: %v", err)
+				continue
+			}
+			if original.Count != 1 {
+				t.Errorf("%v: expected unrelated aggregate to stay at Count 1, got %d", name, original.Count)
+			}
+		default:
+			// nullify fields set by infrastructure
+			received := fakeClient.Data[checkPath]
+			var event api.Event
+			if err := testapi.Codec().DecodeInto([]byte(received.R.Node.Value), &event); err != nil {
+				t.Errorf("unexpected error: %v", err)
+				continue
+			}
+			event.ObjectMeta.CreationTimestamp = util.Time{}
+			event.ObjectMeta.UID = ""
+			received.R.Node.Value = runtime.EncodeOrDie(testapi.Codec(), &event)
+
+			if e, a := item.expect, received; !reflect.DeepEqual(e, a) {
+				t.Errorf("%v:\n%s", name, util.ObjectDiff(e, a))
+			}
 		}
 	}
 }
 
+// TestEventCreateAggregatesRealSeed drives the actual seed-then-fold path
+// through two real Create calls instead of hand-seeding an aggregate
+// record, so it also catches the seed not being stamped with a usable
+// LastTimestamp (a hand-seeded aggregate can't tell the difference, since
+// it sets LastTimestamp itself).
+func TestEventCreateAggregatesRealSeed(t *testing.T) {
+	ctx := api.NewDefaultContext()
+	first := &api.Event{
+		ObjectMeta:     api.ObjectMeta{Name: "foo1", Namespace: api.NamespaceDefault},
+		Reason:         "forTesting",
+		InvolvedObject: api.ObjectReference{Name: "bar", Namespace: api.NamespaceDefault},
+	}
+	second := &api.Event{
+		ObjectMeta:     api.ObjectMeta{Name: "foo2", Namespace: api.NamespaceDefault},
+		Reason:         "forTesting",
+		InvolvedObject: api.ObjectReference{Name: "bar", Namespace: api.NamespaceDefault},
+	}
+
+	fakeClient, storage := NewTestEventStorage(t)
+
+	if _, err := storage.Create(ctx, first); err != nil {
+		t.Fatalf("unexpected error creating the first event: %v", err)
+	}
+	if _, err := storage.Create(ctx, second); err != nil {
+		t.Fatalf("unexpected error creating the second event: %v", err)
+	}
+
+	aggPath, err := aggregatedEventKeyFunc(ctx, first)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	aggPath = etcdtest.AddPrefix(aggPath)
+
+	node := fakeClient.Data[aggPath]
+	if node.R == nil || node.R.Node == nil {
+		t.Fatalf("expected an aggregate record at %s", aggPath)
+	}
+	var aggregate api.Event
+	if err := testapi.Codec().DecodeInto([]byte(node.R.Node.Value), &aggregate); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if aggregate.Count != 2 {
+		t.Errorf("expected the real seed-then-fold path to produce Count 2, got %d", aggregate.Count)
+	}
+}
+
 func TestEventUpdate(t *testing.T) {
 	eventA := &api.Event{
 		ObjectMeta:     api.ObjectMeta{Name: "foo", Namespace: api.NamespaceDefault},
@@ -240,3 +405,341 @@ func TestEventUpdate(t *testing.T) {
 		}
 	}
 }
+
+// NewTestEventStorageMemory builds event REST storage against the
+// in-memory backend instead of tools.FakeEtcdClient, to prove the REST
+// layer doesn't secretly depend on etcd.
+func NewTestEventStorageMemory(t *testing.T) *REST {
+	storage, err := NewStorageForBackend("memory", nil, testTTL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return storage
+}
+
+func TestEventCreateMemoryBackend(t *testing.T) {
+	ctx := api.NewDefaultContext()
+	event := &api.Event{
+		ObjectMeta:     api.ObjectMeta{Name: "foo", Namespace: api.NamespaceDefault},
+		Reason:         "forTesting",
+		InvolvedObject: api.ObjectReference{Name: "bar", Namespace: api.NamespaceDefault},
+	}
+
+	// sameName reuses event's name but belongs to a different series (another
+	// reason), so it is not folded into event's aggregate and collides on write.
+	sameName := &api.Event{
+		ObjectMeta:     api.ObjectMeta{Name: "foo", Namespace: api.NamespaceDefault},
+		Reason:         "forTestingAgain",
+		InvolvedObject: api.ObjectReference{Name: "bar", Namespace: api.NamespaceDefault},
+	}
+
+	storage := NewTestEventStorageMemory(t)
+	if _, err := storage.Create(ctx, event); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := storage.Create(ctx, sameName); err == nil {
+		t.Errorf("expected an already-exists error on the second create of a same-name event")
+	} else if !errors.IsAlreadyExists(err) {
+		t.Errorf("expected an already-exists error, got: %v", err)
+	}
+}
+
+func TestEventUpdateMemoryBackend(t *testing.T) {
+	ctx := api.NewDefaultContext()
+	event := &api.Event{
+		ObjectMeta:     api.ObjectMeta{Name: "foo", Namespace: api.NamespaceDefault},
+		Reason:         "forTesting",
+		InvolvedObject: api.ObjectReference{Name: "bar", Namespace: api.NamespaceDefault},
+	}
+	updated := &api.Event{
+		ObjectMeta:     api.ObjectMeta{Name: "foo", Namespace: api.NamespaceDefault},
+		Reason:         "forTestingAgain",
+		InvolvedObject: api.ObjectReference{Name: "bar", Namespace: api.NamespaceDefault},
+	}
+
+	storage := NewTestEventStorageMemory(t)
+	if _, _, err := storage.Update(ctx, event); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := storage.Update(ctx, updated); err != nil {
+		t.Fatalf("unexpected error updating: %v", err)
+	}
+
+	obj, err := storage.Get(ctx, "foo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := obj.(*api.Event).Reason; got != "forTestingAgain" {
+		t.Errorf("expected updated Reason %q, got %q", "forTestingAgain", got)
+	}
+}
+
+func TestEventCreateCollection(t *testing.T) {
+	ctx := api.NewDefaultContext()
+
+	list := &api.EventList{Items: []api.Event{
+		{
+			ObjectMeta:     api.ObjectMeta{Name: "foo1", Namespace: api.NamespaceDefault},
+			Reason:         "reasonOne",
+			InvolvedObject: api.ObjectReference{Name: "bar", Namespace: api.NamespaceDefault},
+		},
+		{
+			ObjectMeta:     api.ObjectMeta{Name: "foo2", Namespace: api.NamespaceDefault},
+			Reason:         "reasonTwo",
+			InvolvedObject: api.ObjectReference{Name: "bar", Namespace: api.NamespaceDefault},
+		},
+		{
+			ObjectMeta:     api.ObjectMeta{Name: "foo3", Namespace: api.NamespaceDefault},
+			Reason:         "reasonThree",
+			InvolvedObject: api.ObjectReference{Name: "bar", Namespace: api.NamespaceDefault},
+		},
+	}}
+
+	fakeClient, storage := NewTestEventStorage(t)
+	out, errs := storage.CreateCollection(ctx, list)
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("item %d: unexpected error: %v", i, err)
+		}
+	}
+	outList, ok := out.(*api.EventList)
+	if !ok || len(outList.Items) != len(list.Items) {
+		t.Fatalf("expected %d created events, got %#v", len(list.Items), out)
+	}
+
+	var lastIndex uint64
+	for i, item := range list.Items {
+		path, err := etcdgeneric.NamespaceKeyFunc(ctx, "/events", item.Name)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		path = etcdtest.AddPrefix(path)
+		node := fakeClient.Data[path]
+		if node.R == nil || node.R.Node == nil {
+			t.Fatalf("item %d: expected a stored node at %s", i, path)
+		}
+		if node.R.Node.ModifiedIndex <= lastIndex {
+			t.Errorf("item %d: expected ModifiedIndex to progress past %d, got %d", i, lastIndex, node.R.Node.ModifiedIndex)
+		}
+		lastIndex = node.R.Node.ModifiedIndex
+	}
+}
+
+// TestEventCreateCollectionValidationRejectsWholeBatch checks that a single
+// invalid item stops the whole batch from being written, and that the
+// returned error slice still has one entry per input item (nil for the
+// items that were otherwise fine), per the BulkCreater contract.
+func TestEventCreateCollectionValidationRejectsWholeBatch(t *testing.T) {
+	ctx := api.NewDefaultContext()
+
+	list := &api.EventList{Items: []api.Event{
+		{
+			ObjectMeta:     api.ObjectMeta{Name: "foo1", Namespace: api.NamespaceDefault},
+			Reason:         "reasonOne",
+			InvolvedObject: api.ObjectReference{Name: "bar", Namespace: api.NamespaceDefault},
+		},
+		{
+			// no Name and no GenerateName: fails validation.
+			ObjectMeta:     api.ObjectMeta{Namespace: api.NamespaceDefault},
+			Reason:         "reasonTwo",
+			InvolvedObject: api.ObjectReference{Name: "bar", Namespace: api.NamespaceDefault},
+		},
+	}}
+
+	fakeClient, storage := NewTestEventStorage(t)
+	out, errs := storage.CreateCollection(ctx, list)
+	if out != nil {
+		t.Errorf("expected no output on a batch that fails validation, got %#v", out)
+	}
+	if len(errs) != len(list.Items) {
+		t.Fatalf("expected one error slot per input item (%d), got %d: %#v", len(list.Items), len(errs), errs)
+	}
+	if errs[0] != nil {
+		t.Errorf("expected the valid item's error slot to be nil, got %v", errs[0])
+	}
+	if errs[1] == nil {
+		t.Errorf("expected the invalid item's error slot to be set")
+	}
+
+	path, err := etcdgeneric.NamespaceKeyFunc(ctx, "/events", "foo1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	path = etcdtest.AddPrefix(path)
+	if node := fakeClient.Data[path]; node.R != nil && node.R.Node != nil {
+		t.Errorf("expected nothing written for foo1 since the batch was rejected before any write, got %#v", node)
+	}
+}
+
+// TestEventCreateCollectionPartialWriteFailure exercises a batch that
+// passes validation but fails partway through the actual writes: the
+// second item collides with an event that already exists at its key. The
+// first and third items must still be written, and the error slice must
+// line up with the input by index.
+func TestEventCreateCollectionPartialWriteFailure(t *testing.T) {
+	ctx := api.NewDefaultContext()
+
+	list := &api.EventList{Items: []api.Event{
+		{
+			ObjectMeta:     api.ObjectMeta{Name: "foo1", Namespace: api.NamespaceDefault},
+			Reason:         "reasonOne",
+			InvolvedObject: api.ObjectReference{Name: "bar", Namespace: api.NamespaceDefault},
+		},
+		{
+			ObjectMeta:     api.ObjectMeta{Name: "foo2", Namespace: api.NamespaceDefault},
+			Reason:         "reasonTwo",
+			InvolvedObject: api.ObjectReference{Name: "bar", Namespace: api.NamespaceDefault},
+		},
+		{
+			ObjectMeta:     api.ObjectMeta{Name: "foo3", Namespace: api.NamespaceDefault},
+			Reason:         "reasonThree",
+			InvolvedObject: api.ObjectReference{Name: "bar", Namespace: api.NamespaceDefault},
+		},
+	}}
+
+	fakeClient, storage := NewTestEventStorage(t)
+
+	// Seed an existing object at foo2's key so that item collides on write
+	// (not on validation, and not via the aggregation path, since its
+	// aggregate key is untouched).
+	foo2Path, err := etcdgeneric.NamespaceKeyFunc(ctx, "/events", "foo2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	foo2Path = etcdtest.AddPrefix(foo2Path)
+	fakeClient.Data[foo2Path] = tools.EtcdResponseWithError{
+		R: &etcd.Response{
+			Node: &etcd.Node{
+				Value:         runtime.EncodeOrDie(testapi.Codec(), &list.Items[1]),
+				ModifiedIndex: 1,
+				CreatedIndex:  1,
+				TTL:           int64(testTTL),
+			},
+		},
+	}
+
+	out, errs := storage.CreateCollection(ctx, list)
+	if len(errs) != len(list.Items) {
+		t.Fatalf("expected one error slot per input item (%d), got %d: %#v", len(list.Items), len(errs), errs)
+	}
+	if errs[0] != nil {
+		t.Errorf("item 0: unexpected error: %v", errs[0])
+	}
+	if errs[1] == nil || !errors.IsAlreadyExists(errs[1]) {
+		t.Errorf("item 1: expected an already-exists error, got %v", errs[1])
+	}
+	if errs[2] != nil {
+		t.Errorf("item 2: unexpected error: %v", errs[2])
+	}
+
+	outList, ok := out.(*api.EventList)
+	if !ok || len(outList.Items) != 2 {
+		t.Fatalf("expected the 2 successful items in the output, got %#v", out)
+	}
+}
+
+// TestEventCreateRateLimiting drives a fake clock forward to verify that a
+// source which exhausts its burst is throttled, and that it recovers once
+// enough time has passed for its token bucket to refill.
+func TestEventCreateRateLimiting(t *testing.T) {
+	ctx := api.NewDefaultContext()
+	now := time.Unix(0, 0)
+	clock := func() time.Time { return now }
+
+	storage, err := NewStorageForBackend("memory", nil, testTTL,
+		WithEventQPS(1),
+		WithEventBurst(2),
+		WithPerNamespaceBurst(2),
+		withClock(clock),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	newEvent := func(name, reason string) *api.Event {
+		return &api.Event{
+			ObjectMeta:     api.ObjectMeta{Name: name, Namespace: api.NamespaceDefault},
+			Reason:         reason,
+			Source:         api.EventSource{Component: "kubelet", Host: "node1"},
+			InvolvedObject: api.ObjectReference{Name: "bar", Namespace: api.NamespaceDefault},
+		}
+	}
+
+	// The burst of 2 should admit the first two distinct events outright.
+	if _, err := storage.Create(ctx, newEvent("e1", "reasonOne")); err != nil {
+		t.Fatalf("unexpected error admitting within burst: %v", err)
+	}
+	if _, err := storage.Create(ctx, newEvent("e2", "reasonTwo")); err != nil {
+		t.Fatalf("unexpected error admitting within burst: %v", err)
+	}
+
+	// The third, still within the same instant, should be throttled.
+	_, err = storage.Create(ctx, newEvent("e3", "reasonThree"))
+	if err == nil {
+		t.Fatalf("expected the third rapid create to be throttled")
+	}
+	if !errors.IsTooManyRequests(err) {
+		t.Errorf("expected a too-many-requests error, got: %v", err)
+	}
+
+	// Advance the fake clock by enough for one token to regenerate at 1 QPS.
+	now = now.Add(2 * time.Second)
+
+	if _, err := storage.Create(ctx, newEvent("e4", "reasonFour")); err != nil {
+		t.Errorf("expected recovery after the clock advanced, got: %v", err)
+	}
+}
+
+// TestEventCreateNamespaceRateLimiting checks that the per-namespace budget
+// throttles a namespace across multiple distinct sources, independently of
+// each source's own (much larger) per-source budget.
+func TestEventCreateNamespaceRateLimiting(t *testing.T) {
+	ctx := api.NewDefaultContext()
+	now := time.Unix(0, 0)
+	clock := func() time.Time { return now }
+
+	storage, err := NewStorageForBackend("memory", nil, testTTL,
+		WithEventQPS(100),
+		WithEventBurst(100),
+		WithPerNamespaceQPS(1),
+		WithPerNamespaceBurst(2),
+		withClock(clock),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	newEvent := func(name, component string) *api.Event {
+		return &api.Event{
+			ObjectMeta:     api.ObjectMeta{Name: name, Namespace: api.NamespaceDefault},
+			Reason:         "forTesting",
+			Source:         api.EventSource{Component: component, Host: "node1"},
+			InvolvedObject: api.ObjectReference{Name: "bar", Namespace: api.NamespaceDefault},
+		}
+	}
+
+	// Two distinct sources exhaust the namespace's burst of 2, even though
+	// neither is anywhere near its own (much larger) per-source budget.
+	if _, err := storage.Create(ctx, newEvent("e1", "componentA")); err != nil {
+		t.Fatalf("unexpected error admitting within namespace burst: %v", err)
+	}
+	if _, err := storage.Create(ctx, newEvent("e2", "componentB")); err != nil {
+		t.Fatalf("unexpected error admitting within namespace burst: %v", err)
+	}
+
+	// A third, still-distinct source is throttled by the namespace cap.
+	_, err = storage.Create(ctx, newEvent("e3", "componentC"))
+	if err == nil {
+		t.Fatalf("expected the namespace budget to throttle a third distinct source")
+	}
+	if !errors.IsTooManyRequests(err) {
+		t.Errorf("expected a too-many-requests error, got: %v", err)
+	}
+
+	// Advance the fake clock by enough for one token to regenerate at 1 QPS.
+	now = now.Add(2 * time.Second)
+
+	if _, err := storage.Create(ctx, newEvent("e4", "componentD")); err != nil {
+		t.Errorf("expected recovery after the clock advanced, got: %v", err)
+	}
+}
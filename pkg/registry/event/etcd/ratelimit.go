@@ -0,0 +1,145 @@
+/*
+Copyright 2014 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package etcd
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/api/errors"
+)
+
+// tooManyEventsError builds the error Create/Update return when e is over
+// budget, with a Retry-After hint for how long the caller should back off.
+func tooManyEventsError(e *api.Event, retryAfter time.Duration) error {
+	seconds := int(retryAfter.Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+	return errors.NewTooManyRequests(
+		fmt.Sprintf("event budget exceeded for %s, retry later", sourceRateLimitKey(e)),
+		seconds,
+	)
+}
+
+const (
+	// defaultEventQPS is the steady-state rate, per (namespace, source
+	// component, source host), at which Create/Update calls are admitted.
+	defaultEventQPS = 25.0
+
+	// defaultEventBurst is how many events a single source may write back
+	// to back before the steady-state rate kicks in.
+	defaultEventBurst = 50
+
+	// defaultPerNamespaceQPS bounds the combined rate of all sources within
+	// a single namespace, on top of each source's own budget.
+	defaultPerNamespaceQPS = 250.0
+)
+
+// tokenBucket is a small, mutex-protected token-bucket rate limiter. Time is
+// taken from an injectable clock so tests can drive it forward deterministically.
+type tokenBucket struct {
+	mu     sync.Mutex
+	tokens float64
+	qps    float64
+	burst  float64
+	last   time.Time
+}
+
+func newTokenBucket(qps float64, burst float64, now time.Time) *tokenBucket {
+	return &tokenBucket{tokens: burst, qps: qps, burst: burst, last: now}
+}
+
+// reserve refills the bucket up to now and reports whether a token is
+// available. If checkOnly is false and a token is available, it is consumed.
+// The returned duration is how long the caller should wait before retrying
+// when no token was available.
+func (b *tokenBucket) reserve(now time.Time, checkOnly bool) (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens += elapsed * b.qps
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+	}
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / b.qps * float64(time.Second))
+		return false, wait
+	}
+	if !checkOnly {
+		b.tokens--
+	}
+	return true, 0
+}
+
+// sourceRateLimitKey identifies the token bucket an event's writes are
+// charged against: its namespace plus the component/host that produced it.
+func sourceRateLimitKey(e *api.Event) string {
+	return fmt.Sprintf("%s/%s/%s", e.Namespace, e.Source.Component, e.Source.Host)
+}
+
+// now returns the REST's notion of the current time, defaulting to
+// time.Now but overridable in tests so they can drive the limiter forward
+// without sleeping.
+func (r *REST) now() time.Time {
+	if r.nowFunc != nil {
+		return r.nowFunc()
+	}
+	return time.Now()
+}
+
+// allow reports whether e is within budget for both its own source and its
+// namespace as a whole. Tokens are only consumed from both buckets when
+// both would allow the event through, so a source that's under its own
+// budget can still be throttled by a noisy namespace and vice versa without
+// either bucket drifting out of sync.
+func (r *REST) allow(e *api.Event) (bool, time.Duration) {
+	now := r.now()
+
+	r.limitersMu.Lock()
+	source := r.sourceLimiters[sourceRateLimitKey(e)]
+	if source == nil {
+		source = newTokenBucket(r.qps, float64(r.burst), now)
+		r.sourceLimiters[sourceRateLimitKey(e)] = source
+	}
+	namespace := r.namespaceLimiters[e.Namespace]
+	if namespace == nil {
+		namespace = newTokenBucket(r.perNamespaceQPS, float64(r.perNamespaceBurst), now)
+		r.namespaceLimiters[e.Namespace] = namespace
+	}
+	r.limitersMu.Unlock()
+
+	srcOK, srcWait := source.reserve(now, true)
+	nsOK, nsWait := namespace.reserve(now, true)
+	if !srcOK || !nsOK {
+		wait := srcWait
+		if nsWait > wait {
+			wait = nsWait
+		}
+		return false, wait
+	}
+
+	source.reserve(now, false)
+	namespace.reserve(now, false)
+	return true, 0
+}
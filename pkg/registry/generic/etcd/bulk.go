@@ -0,0 +1,42 @@
+/*
+Copyright 2014 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package etcd
+
+import (
+	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/runtime"
+)
+
+// BulkCreater is implemented by registries that accept a list of objects to
+// create as a single logical operation instead of making the caller issue
+// a separate Create per item. list and the returned runtime.Object are both
+// expected to be pointers to the same list type (e.g. *api.EventList);
+// the interface is kept in terms of runtime.Object so callers that only
+// have a rest.Storage in hand can type-assert to BulkCreater without
+// depending on any particular resource's package.
+//
+// This does not promise the batch is written in one atomic backend
+// transaction; etcd v2 has no such primitive, so implementations backed by
+// it can only validate the whole batch up front and then issue one write
+// per item. Implementations must reject the whole batch before writing
+// anything if any item fails validation, and otherwise return one error
+// per item, in the same order as the input (including a nil entry for
+// every item that was created successfully, and for every valid item in a
+// batch rejected at validation time).
+type BulkCreater interface {
+	CreateCollection(ctx api.Context, list runtime.Object) (runtime.Object, []error)
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2014 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package etcd
+
+import (
+	"fmt"
+
+	"k8s.io/kubernetes/pkg/runtime"
+	"k8s.io/kubernetes/pkg/storage"
+	"k8s.io/kubernetes/pkg/tools"
+)
+
+// Config is the config value the "etcd" storage.Factory expects: an etcd
+// client (real or, in tests, tools.FakeEtcdClient), the codec to
+// encode/decode objects with, and the key prefix to store them under.
+type Config struct {
+	Client tools.EtcdClient
+	Codec  runtime.Codec
+	Prefix string
+}
+
+func init() {
+	storage.RegisterBackend("etcd", func(config interface{}) (storage.Interface, error) {
+		cfg, ok := config.(Config)
+		if !ok {
+			return nil, fmt.Errorf("etcd backend: expected etcd.Config, got %#v", config)
+		}
+		return NewEtcdStorage(cfg.Client, cfg.Codec, cfg.Prefix), nil
+	})
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2014 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Factory builds a backend-specific storage.Interface from a
+// backend-specific config value. Backends register a Factory under a name
+// with RegisterBackend; callers then select a backend by name (for example
+// from a command-line flag) without importing the backend package directly.
+type Factory func(config interface{}) (Interface, error)
+
+var (
+	backendsMu sync.Mutex
+	backends   = map[string]Factory{}
+)
+
+// RegisterBackend makes a storage backend available under name, typically
+// called from the backend package's init(). It panics if another backend is
+// already registered under the same name.
+func RegisterBackend(name string, factory Factory) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	if _, exists := backends[name]; exists {
+		panic(fmt.Sprintf("storage: backend %q already registered", name))
+	}
+	backends[name] = factory
+}
+
+// NewStorage builds a storage.Interface using the backend registered under
+// name, passing it config. Callers typically blank-import the desired
+// backend package so its init() has registered by the time NewStorage runs.
+func NewStorage(name string, config interface{}) (Interface, error) {
+	backendsMu.Lock()
+	factory, ok := backends[name]
+	backendsMu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("storage: no backend registered under name %q (missing import?)", name)
+	}
+	return factory(config)
+}
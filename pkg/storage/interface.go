@@ -0,0 +1,73 @@
+/*
+Copyright 2014 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package storage defines the backend-neutral interface that the generic
+// registries build on, plus a Factory registry so a concrete backend (etcd,
+// an in-memory store, or something else entirely) can be selected by name
+// instead of being wired in by the caller.
+package storage
+
+import (
+	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/runtime"
+)
+
+// ResponseMeta carries information about a stored object that isn't part of
+// its content, such as the TTL it is currently stored with.
+type ResponseMeta struct {
+	// TTL is the time to live, in seconds, remaining for the object. Zero
+	// means the object does not expire.
+	TTL int64
+}
+
+// UpdateFunc is called by Interface.GuaranteedUpdate to compute the desired
+// new state of an object from its current state. input is nil if no object
+// currently exists at the key and the update is allowed to create one.
+// Returning a nil ttl leaves the object's current TTL unchanged.
+type UpdateFunc func(input runtime.Object, res ResponseMeta) (output runtime.Object, ttl *uint64, err error)
+
+// Interface is the set of operations a storage backend must implement to
+// sit underneath a generic registry (see pkg/registry/generic/etcd.Etcd).
+// Concrete backends register a Factory that produces an Interface with
+// RegisterBackend instead of being imported directly by registries, so that
+// a registry written against Interface works unmodified against etcd, an
+// in-memory store used in tests, or any other backend.
+type Interface interface {
+	// Create adds a new object at key, failing if one already exists there.
+	// ttl is the time to live in seconds; zero means no expiration.
+	Create(ctx api.Context, key string, obj, out runtime.Object, ttl uint64) error
+
+	// Set writes obj at key regardless of whether something already exists
+	// there.
+	Set(ctx api.Context, key string, obj, out runtime.Object, ttl uint64) error
+
+	// Get retrieves the object at key into objPtr. If ignoreNotFound is
+	// true, a missing key is not an error and objPtr is left at its zero
+	// value.
+	Get(ctx api.Context, key string, objPtr runtime.Object, ignoreNotFound bool) error
+
+	// Delete removes the object at key, writing it into out.
+	Delete(ctx api.Context, key string, out runtime.Object) error
+
+	// GuaranteedUpdate reads the object at key, calls tryUpdate with it (or
+	// with nil if ignoreNotFound is true and nothing exists at key yet),
+	// and atomically writes back the result into ptrToType.
+	GuaranteedUpdate(ctx api.Context, key string, ptrToType runtime.Object, ignoreNotFound bool, tryUpdate UpdateFunc) error
+
+	// List retrieves all objects under key into listObj, which must be a
+	// pointer to a list type.
+	List(ctx api.Context, key string, listObj runtime.Object) error
+}
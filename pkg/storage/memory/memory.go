@@ -0,0 +1,185 @@
+/*
+Copyright 2014 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package memory implements a storage.Interface entirely in process memory.
+// It is registered under the name "memory" and is meant for tests and
+// single-process experimentation, not for production use: nothing is
+// persisted, so no item outlives the process that created it, and an item
+// stored with a TTL is dropped once that TTL has passed.
+package memory
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/api/errors"
+	"k8s.io/kubernetes/pkg/api/meta"
+	"k8s.io/kubernetes/pkg/runtime"
+	"k8s.io/kubernetes/pkg/storage"
+)
+
+func init() {
+	storage.RegisterBackend("memory", func(config interface{}) (storage.Interface, error) {
+		return NewStorage(), nil
+	})
+}
+
+type entry struct {
+	obj       runtime.Object
+	expiresAt time.Time // zero means it never expires
+}
+
+// store is an in-memory storage.Interface keyed by the flat etcd-style
+// paths the generic registries already produce, guarded by a single mutex.
+// It makes no attempt at persistence, watch support, or multi-process
+// coordination.
+type store struct {
+	mu   sync.Mutex
+	data map[string]entry
+}
+
+// NewStorage returns a storage.Interface backed by an in-memory map. It is
+// also available under the "memory" name via storage.NewStorage.
+func NewStorage() storage.Interface {
+	return &store{data: map[string]entry{}}
+}
+
+func (s *store) get(key string) (runtime.Object, bool) {
+	e, ok := s.data[key]
+	if !ok {
+		return nil, false
+	}
+	if !e.expiresAt.IsZero() && time.Now().After(e.expiresAt) {
+		delete(s.data, key)
+		return nil, false
+	}
+	return e.obj, true
+}
+
+func (s *store) put(key string, obj runtime.Object, ttl uint64) {
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = time.Now().Add(time.Duration(ttl) * time.Second)
+	}
+	s.data[key] = entry{obj: obj, expiresAt: expiresAt}
+}
+
+func (s *store) Create(ctx api.Context, key string, obj, out runtime.Object, ttl uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.get(key); exists {
+		return errors.NewAlreadyExists("", key)
+	}
+	s.put(key, obj, ttl)
+	return copyInto(obj, out)
+}
+
+func (s *store) Set(ctx api.Context, key string, obj, out runtime.Object, ttl uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.put(key, obj, ttl)
+	return copyInto(obj, out)
+}
+
+func (s *store) Get(ctx api.Context, key string, objPtr runtime.Object, ignoreNotFound bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, ok := s.get(key)
+	if !ok {
+		if ignoreNotFound {
+			return nil
+		}
+		return errors.NewNotFound("", key)
+	}
+	return copyInto(existing, objPtr)
+}
+
+func (s *store) Delete(ctx api.Context, key string, out runtime.Object) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, ok := s.get(key)
+	if !ok {
+		return errors.NewNotFound("", key)
+	}
+	delete(s.data, key)
+	return copyInto(existing, out)
+}
+
+func (s *store) GuaranteedUpdate(ctx api.Context, key string, ptrToType runtime.Object, ignoreNotFound bool, tryUpdate storage.UpdateFunc) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.get(key)
+	if !ok && !ignoreNotFound {
+		return errors.NewNotFound("", key)
+	}
+
+	var ttl int64
+	if e, ok := s.data[key]; ok && !e.expiresAt.IsZero() {
+		ttl = int64(time.Until(e.expiresAt).Seconds())
+	}
+
+	var input runtime.Object
+	if ok {
+		input = existing
+	}
+	updated, newTTL, err := tryUpdate(input, storage.ResponseMeta{TTL: ttl})
+	if err != nil {
+		return err
+	}
+
+	if newTTL == nil {
+		// keep the current expiry; a key with nothing stored yet never expires.
+		s.data[key] = entry{obj: updated, expiresAt: s.data[key].expiresAt}
+		return copyInto(updated, ptrToType)
+	}
+	s.put(key, updated, *newTTL)
+	return copyInto(updated, ptrToType)
+}
+
+func (s *store) List(ctx api.Context, key string, listObj runtime.Object) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items := []runtime.Object{}
+	for k, e := range s.data {
+		if !hasPrefix(k, key) {
+			continue
+		}
+		if !e.expiresAt.IsZero() && time.Now().After(e.expiresAt) {
+			continue
+		}
+		items = append(items, e.obj)
+	}
+	return meta.SetList(listObj, items)
+}
+
+func hasPrefix(s, prefix string) bool {
+	return len(s) >= len(prefix) && s[:len(prefix)] == prefix
+}
+
+// copyInto assigns src's underlying value into dst, which must be a pointer
+// to the same concrete type. It is not a deep copy: callers must treat the
+// objects handed back from this store as immutable.
+func copyInto(src, dst runtime.Object) error {
+	if dst == nil || src == nil {
+		return nil
+	}
+	reflect.ValueOf(dst).Elem().Set(reflect.ValueOf(src).Elem())
+	return nil
+}